@@ -0,0 +1,337 @@
+// Package credential provides password hashing and credential storage for
+// UserService, with Argon2id as the default hash and bcrypt kept around as a
+// read path for migrating older hashes.
+package credential
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func b64Encode(b []byte) string { return base64.RawStdEncoding.EncodeToString(b) }
+
+func b64Decode(s string) ([]byte, error) { return base64.RawStdEncoding.DecodeString(s) }
+
+// Hasher hashes and verifies passwords, and knows whether a previously
+// stored hash should be upgraded to the hasher's current parameters.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(encodedHash, password string) (bool, error)
+	NeedsRehash(encodedHash string) bool
+}
+
+// CredentialStore persists username -> password hash pairs, plus any
+// WebAuthn credentials registered as a second factor or passwordless login
+// method for that username.
+type CredentialStore interface {
+	Register(username, passwordHash string) error
+	Lookup(username string) (passwordHash string, err error)
+	UpdatePassword(username, passwordHash string) error
+
+	SaveWebAuthnCredential(username string, cred WebAuthnCredential) error
+	WebAuthnCredentials(username string) ([]WebAuthnCredential, error)
+	UpdateWebAuthnSignCount(username string, credentialID []byte, signCount uint32) error
+}
+
+// WebAuthnCredential is a registered FIDO2 authenticator credential, as
+// produced by the webauthn package's FinishRegistration.
+type WebAuthnCredential struct {
+	ID         []byte
+	PublicKey  []byte // COSE_Key, CBOR-encoded
+	SignCount  uint32
+	Transports []string
+	AAGUID     []byte
+}
+
+// Argon2Params configures an Argon2idHasher. The zero value is not usable;
+// use DefaultArgon2Params.
+type Argon2Params struct {
+	Time        uint32
+	MemoryKiB   uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params returns the OWASP-recommended starting point for
+// interactive login (19 MiB, 2 passes, one lane would be too slow under
+// load, so we use 4).
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Time:        2,
+		MemoryKiB:   19 * 1024,
+		Parallelism: 4,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Argon2idHasher is the default Hasher, encoding hashes as the standard PHC
+// string: $argon2id$v=19$m=...,t=...,p=...$salt$hash.
+type Argon2idHasher struct {
+	Params Argon2Params
+}
+
+// NewArgon2idHasher returns a Hasher using the given parameters.
+func NewArgon2idHasher(params Argon2Params) *Argon2idHasher {
+	return &Argon2idHasher{Params: params}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.Params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("argon2id: generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Params.Time, h.Params.MemoryKiB, h.Params.Parallelism, h.Params.KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.Params.MemoryKiB, h.Params.Time, h.Params.Parallelism,
+		b64Encode(salt), b64Encode(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(encodedHash, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Time, params.MemoryKiB, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+}
+
+// NeedsRehash reports whether encodedHash was produced with weaker
+// parameters than h.Params, so Authenticate can transparently upgrade it.
+func (h *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, _, _, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.Time < h.Params.Time ||
+		params.MemoryKiB < h.Params.MemoryKiB ||
+		params.Parallelism < h.Params.Parallelism
+}
+
+func decodeArgon2id(encodedHash string) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2Params{}, nil, nil, fmt.Errorf("credential: not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("credential: bad version segment: %w", err)
+	}
+	if version != argon2.Version {
+		return Argon2Params{}, nil, nil, fmt.Errorf("credential: unsupported argon2 version %d", version)
+	}
+
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.MemoryKiB, &params.Time, &params.Parallelism); err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("credential: bad params segment: %w", err)
+	}
+
+	salt, err := b64Decode(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("credential: bad salt: %w", err)
+	}
+	key, err := b64Decode(parts[5])
+	if err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("credential: bad key: %w", err)
+	}
+
+	return params, salt, key, nil
+}
+
+// BcryptHasher is kept as a read (and rehash-on-login) path for accounts
+// created before the switch to Argon2id. Its Hash still produces bcrypt
+// hashes; as MigratingHasher's Legacy it is only used to verify.
+type BcryptHasher struct {
+	Cost int
+}
+
+// NewBcryptHasher returns a Hasher wrapping golang.org/x/crypto/bcrypt at the
+// given cost.
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{Cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", fmt.Errorf("bcrypt: %w", err)
+	}
+	return string(b), nil
+}
+
+func (h *BcryptHasher) Verify(encodedHash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return false, err
+}
+
+// NeedsRehash always returns true: any bcrypt hash should be upgraded to
+// Argon2id the next time its owner logs in successfully.
+func (h *BcryptHasher) NeedsRehash(string) bool { return true }
+
+// MigratingHasher verifies against whichever of Current or Legacy produced
+// encodedHash (detected by its PHC/bcrypt prefix), but always hashes and
+// reports rehash decisions using Current.
+type MigratingHasher struct {
+	Current Hasher
+	Legacy  Hasher
+}
+
+func (h *MigratingHasher) Hash(password string) (string, error) {
+	return h.Current.Hash(password)
+}
+
+func (h *MigratingHasher) Verify(encodedHash, password string) (bool, error) {
+	if strings.HasPrefix(encodedHash, "$argon2id$") {
+		return h.Current.Verify(encodedHash, password)
+	}
+	return h.Legacy.Verify(encodedHash, password)
+}
+
+func (h *MigratingHasher) NeedsRehash(encodedHash string) bool {
+	if strings.HasPrefix(encodedHash, "$argon2id$") {
+		return h.Current.NeedsRehash(encodedHash)
+	}
+	return true
+}
+
+// InMemoryCredentialStore is a map-backed CredentialStore reference
+// implementation, guarded by a mutex.
+type InMemoryCredentialStore struct {
+	mu          sync.Mutex
+	hashs       map[string]string
+	webauthnCts map[string][]WebAuthnCredential
+}
+
+// NewInMemoryCredentialStore returns an empty CredentialStore.
+func NewInMemoryCredentialStore() *InMemoryCredentialStore {
+	return &InMemoryCredentialStore{
+		hashs:       make(map[string]string),
+		webauthnCts: make(map[string][]WebAuthnCredential),
+	}
+}
+
+func (s *InMemoryCredentialStore) Register(username, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.hashs[username]; exists {
+		return fmt.Errorf("credential: %q already registered", username)
+	}
+	s.hashs[username] = passwordHash
+	return nil
+}
+
+func (s *InMemoryCredentialStore) Lookup(username string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hash, ok := s.hashs[username]
+	if !ok {
+		return "", fmt.Errorf("credential: %q not found", username)
+	}
+	return hash, nil
+}
+
+func (s *InMemoryCredentialStore) UpdatePassword(username, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.hashs[username]; !ok {
+		return fmt.Errorf("credential: %q not found", username)
+	}
+	s.hashs[username] = passwordHash
+	return nil
+}
+
+func (s *InMemoryCredentialStore) SaveWebAuthnCredential(username string, cred WebAuthnCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webauthnCts[username] = append(s.webauthnCts[username], cred)
+	return nil
+}
+
+func (s *InMemoryCredentialStore) WebAuthnCredentials(username string) ([]WebAuthnCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]WebAuthnCredential(nil), s.webauthnCts[username]...), nil
+}
+
+func (s *InMemoryCredentialStore) UpdateWebAuthnSignCount(username string, credentialID []byte, signCount uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range s.webauthnCts[username] {
+		if bytes.Equal(c.ID, credentialID) {
+			s.webauthnCts[username][i].SignCount = signCount
+			return nil
+		}
+	}
+	return fmt.Errorf("credential: webauthn credential not found for %q", username)
+}
+
+// MinEntropyBits is the default minimum password entropy required by
+// CheckStrength.
+const MinEntropyBits = 40.0
+
+// CheckStrength estimates password entropy the way go-password-validator
+// does: bits = length * log2(size of the smallest character set covering
+// every character used). It rejects passwords below minBits.
+func CheckStrength(password string, minBits float64) error {
+	if entropyBits(password) < minBits {
+		return fmt.Errorf("credential: password too weak, need at least %.0f bits of entropy", minBits)
+	}
+	return nil
+}
+
+func entropyBits(password string) float64 {
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	var poolSize float64
+	if hasLower {
+		poolSize += 26
+	}
+	if hasUpper {
+		poolSize += 26
+	}
+	if hasDigit {
+		poolSize += 10
+	}
+	if hasSymbol {
+		poolSize += 32
+	}
+	if poolSize == 0 {
+		return 0
+	}
+
+	return float64(len([]rune(password))) * math.Log2(poolSize)
+}
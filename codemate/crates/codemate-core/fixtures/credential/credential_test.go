@@ -0,0 +1,140 @@
+package credential
+
+import "testing"
+
+func TestArgon2idHasherRoundTrip(t *testing.T) {
+	h := NewArgon2idHasher(DefaultArgon2Params())
+
+	hash, err := h.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	ok, err := h.Verify(hash, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !ok {
+		t.Fatal("Verify returned false for the correct password")
+	}
+
+	ok, err = h.Verify(hash, "wrong password")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if ok {
+		t.Fatal("Verify returned true for the wrong password")
+	}
+}
+
+func TestArgon2idHasherNeedsRehash(t *testing.T) {
+	weak := NewArgon2idHasher(Argon2Params{Time: 1, MemoryKiB: 8 * 1024, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	strong := NewArgon2idHasher(DefaultArgon2Params())
+
+	hash, err := weak.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	if weak.NeedsRehash(hash) {
+		t.Error("weak hasher should not flag its own parameters as needing a rehash")
+	}
+	if !strong.NeedsRehash(hash) {
+		t.Error("strong hasher should flag a hash produced with weaker parameters")
+	}
+}
+
+func TestBcryptHasherRoundTrip(t *testing.T) {
+	h := NewBcryptHasher(4) // low cost to keep the test fast
+
+	hash, err := h.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	ok, err := h.Verify(hash, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !ok {
+		t.Fatal("Verify returned false for the correct password")
+	}
+
+	if !h.NeedsRehash(hash) {
+		t.Error("bcrypt hashes should always be flagged for rehash to argon2id")
+	}
+}
+
+func TestMigratingHasherVerifiesBoth(t *testing.T) {
+	m := &MigratingHasher{
+		Current: NewArgon2idHasher(DefaultArgon2Params()),
+		Legacy:  NewBcryptHasher(4),
+	}
+
+	argon2Hash, err := m.Current.Hash("swordfish")
+	if err != nil {
+		t.Fatalf("Current.Hash: %v", err)
+	}
+	bcryptHash, err := m.Legacy.Hash("swordfish")
+	if err != nil {
+		t.Fatalf("Legacy.Hash: %v", err)
+	}
+
+	if ok, err := m.Verify(argon2Hash, "swordfish"); err != nil || !ok {
+		t.Errorf("Verify(argon2Hash) = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := m.Verify(bcryptHash, "swordfish"); err != nil || !ok {
+		t.Errorf("Verify(bcryptHash) = %v, %v; want true, nil", ok, err)
+	}
+
+	if m.NeedsRehash(argon2Hash) {
+		t.Error("current-format hash at current params should not need a rehash")
+	}
+	if !m.NeedsRehash(bcryptHash) {
+		t.Error("legacy bcrypt hash should always need a rehash")
+	}
+}
+
+func TestInMemoryCredentialStore(t *testing.T) {
+	s := NewInMemoryCredentialStore()
+
+	if err := s.Register("alice", "hash-1"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Register("alice", "hash-2"); err == nil {
+		t.Error("Register should fail for an already-registered username")
+	}
+
+	got, err := s.Lookup("alice")
+	if err != nil || got != "hash-1" {
+		t.Fatalf("Lookup = %q, %v; want %q, nil", got, err, "hash-1")
+	}
+
+	if err := s.UpdatePassword("alice", "hash-2"); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+	if got, _ := s.Lookup("alice"); got != "hash-2" {
+		t.Errorf("Lookup after update = %q; want %q", got, "hash-2")
+	}
+
+	cred := WebAuthnCredential{ID: []byte("cred-1"), SignCount: 1}
+	if err := s.SaveWebAuthnCredential("alice", cred); err != nil {
+		t.Fatalf("SaveWebAuthnCredential: %v", err)
+	}
+	if err := s.UpdateWebAuthnSignCount("alice", cred.ID, 2); err != nil {
+		t.Fatalf("UpdateWebAuthnSignCount: %v", err)
+	}
+	creds, err := s.WebAuthnCredentials("alice")
+	if err != nil || len(creds) != 1 || creds[0].SignCount != 2 {
+		t.Fatalf("WebAuthnCredentials = %+v, %v; want one credential with SignCount 2", creds, err)
+	}
+}
+
+func TestCheckStrength(t *testing.T) {
+	if err := CheckStrength("password", MinEntropyBits); err == nil {
+		t.Error("CheckStrength should reject a low-entropy all-lowercase password")
+	}
+	if err := CheckStrength("Tr0ub4dor&3xplosive!", MinEntropyBits); err != nil {
+		t.Errorf("CheckStrength rejected a high-entropy mixed-class password: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Package extauth accepts users authenticated by an external identity
+// provider that signs its payload with HMAC-SHA256, modeled on the Telegram
+// Login Widget flow: https://core.telegram.org/widgets/login#checking-authorization.
+package extauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"codemate/user"
+)
+
+// IdentityStore maps an external provider's user ID to the internal user it
+// was provisioned as, so repeat logins resolve to the same account instead
+// of creating a new one each time.
+type IdentityStore interface {
+	Lookup(externalID string) (userID int64, ok bool)
+	Save(externalID string, userID int64)
+}
+
+// InMemoryIdentityStore is a map-backed IdentityStore reference
+// implementation, guarded by a mutex.
+type InMemoryIdentityStore struct {
+	mu           sync.Mutex
+	byExternalID map[string]int64
+}
+
+// NewInMemoryIdentityStore returns an empty IdentityStore.
+func NewInMemoryIdentityStore() *InMemoryIdentityStore {
+	return &InMemoryIdentityStore{byExternalID: make(map[string]int64)}
+}
+
+func (s *InMemoryIdentityStore) Lookup(externalID string) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.byExternalID[externalID]
+	return id, ok
+}
+
+func (s *InMemoryIdentityStore) Save(externalID string, userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byExternalID[externalID] = userID
+}
+
+// Verifier checks externally-signed login payloads and provisions users for
+// ones it hasn't seen before.
+type Verifier struct {
+	Users      user.Service
+	Identities IdentityStore
+}
+
+// NewVerifier returns a Verifier backed by users and identities.
+func NewVerifier(users user.Service, identities IdentityStore) *Verifier {
+	return &Verifier{Users: users, Identities: identities}
+}
+
+// VerifyExternalLogin checks params against secret the way the Telegram
+// Login Widget does: the data-check string is every field except "hash",
+// sorted by key and joined as "key=value" with "\n", HMAC-SHA256'd under a
+// key of SHA256(secret), hex-encoded, and compared in constant time to
+// params["hash"]. A payload whose "auth_date" is older than maxAge is
+// rejected to prevent replay. params must contain "id" (the external user's
+// ID) and may contain "username"/"email" to seed a newly provisioned user.
+func (v *Verifier) VerifyExternalLogin(params map[string]string, secret []byte, maxAge time.Duration) (*user.User, error) {
+	if err := checkSignature(params, secret); err != nil {
+		return nil, err
+	}
+	if err := checkAuthDate(params, maxAge); err != nil {
+		return nil, err
+	}
+
+	externalID := params["id"]
+	if externalID == "" {
+		return nil, fmt.Errorf("extauth: params missing %q", "id")
+	}
+
+	if userID, ok := v.Identities.Lookup(externalID); ok {
+		return v.Users.GetUser(userID)
+	}
+
+	username := params["username"]
+	if username == "" {
+		username = "ext_" + externalID
+	}
+	email := params["email"]
+	if email == "" {
+		email = username + "@external.invalid"
+	}
+
+	u, err := v.Users.CreateUser(username, email)
+	if err != nil {
+		return nil, fmt.Errorf("extauth: provision user: %w", err)
+	}
+	v.Identities.Save(externalID, u.ID)
+	return u, nil
+}
+
+func checkSignature(params map[string]string, secret []byte) error {
+	wantHex, ok := params["hash"]
+	if !ok {
+		return fmt.Errorf("extauth: params missing %q", "hash")
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k == "hash" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + params[k]
+	}
+	dataCheckString := strings.Join(pairs, "\n")
+
+	secretKey := sha256.Sum256(secret)
+	mac := hmac.New(sha256.New, secretKey[:])
+	mac.Write([]byte(dataCheckString))
+	got := hex.EncodeToString(mac.Sum(nil))
+
+	if subtle.ConstantTimeCompare([]byte(got), []byte(wantHex)) != 1 {
+		return fmt.Errorf("extauth: signature mismatch")
+	}
+	return nil
+}
+
+func checkAuthDate(params map[string]string, maxAge time.Duration) error {
+	raw, ok := params["auth_date"]
+	if !ok {
+		return fmt.Errorf("extauth: params missing %q", "auth_date")
+	}
+	unixSeconds, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("extauth: invalid auth_date %q: %w", raw, err)
+	}
+	authTime := time.Unix(unixSeconds, 0)
+	if time.Since(authTime) > maxAge {
+		return fmt.Errorf("extauth: auth_date too old, possible replay")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package extauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"codemate/user"
+)
+
+var testSecret = []byte("bot-token-secret")
+
+func signParams(t *testing.T, params map[string]string, secret []byte) {
+	t.Helper()
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + params[k]
+	}
+	dataCheckString := strings.Join(pairs, "\n")
+
+	secretKey := sha256.Sum256(secret)
+	mac := hmac.New(sha256.New, secretKey[:])
+	mac.Write([]byte(dataCheckString))
+	params["hash"] = hex.EncodeToString(mac.Sum(nil))
+}
+
+func validParams(t *testing.T) map[string]string {
+	t.Helper()
+	params := map[string]string{
+		"id":        "12345",
+		"username":  "alice",
+		"auth_date": strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	signParams(t, params, testSecret)
+	return params
+}
+
+func TestVerifyExternalLoginProvisionsNewUser(t *testing.T) {
+	v := NewVerifier(user.NewInMemoryService(), NewInMemoryIdentityStore())
+
+	u, err := v.VerifyExternalLogin(validParams(t), testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("VerifyExternalLogin: %v", err)
+	}
+	if u.Username != "alice" {
+		t.Errorf("Username = %q, want %q", u.Username, "alice")
+	}
+}
+
+func TestVerifyExternalLoginResolvesExistingUser(t *testing.T) {
+	v := NewVerifier(user.NewInMemoryService(), NewInMemoryIdentityStore())
+
+	first, err := v.VerifyExternalLogin(validParams(t), testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("VerifyExternalLogin (first login): %v", err)
+	}
+
+	second, err := v.VerifyExternalLogin(validParams(t), testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("VerifyExternalLogin (repeat login): %v", err)
+	}
+
+	if second.ID != first.ID {
+		t.Errorf("repeat login resolved to user %d, want the original user %d", second.ID, first.ID)
+	}
+}
+
+func TestVerifyExternalLoginRejectsTamperedSignature(t *testing.T) {
+	v := NewVerifier(user.NewInMemoryService(), NewInMemoryIdentityStore())
+
+	params := validParams(t)
+	params["username"] = "mallory"
+
+	if _, err := v.VerifyExternalLogin(params, testSecret, time.Hour); err == nil {
+		t.Error("VerifyExternalLogin should reject a payload whose signature no longer matches its fields")
+	}
+}
+
+func TestVerifyExternalLoginRejectsWrongSecret(t *testing.T) {
+	v := NewVerifier(user.NewInMemoryService(), NewInMemoryIdentityStore())
+
+	if _, err := v.VerifyExternalLogin(validParams(t), []byte("wrong-secret"), time.Hour); err == nil {
+		t.Error("VerifyExternalLogin should reject a payload signed under a different secret")
+	}
+}
+
+func TestVerifyExternalLoginRejectsExpiredAuthDate(t *testing.T) {
+	v := NewVerifier(user.NewInMemoryService(), NewInMemoryIdentityStore())
+
+	params := map[string]string{
+		"id":        "12345",
+		"username":  "alice",
+		"auth_date": strconv.FormatInt(time.Now().Add(-2*time.Hour).Unix(), 10),
+	}
+	signParams(t, params, testSecret)
+
+	if _, err := v.VerifyExternalLogin(params, testSecret, time.Hour); err == nil {
+		t.Error("VerifyExternalLogin should reject an auth_date older than maxAge")
+	}
+}
@@ -1,32 +1,111 @@
 // Sample Go code for testing.
 package main
 
-import "fmt"
+import (
+	"fmt"
 
-// Authenticate authenticates a user with username and password.
-func Authenticate(username, password string) (bool, error) {
-	if username == "" || password == "" {
-		return false, fmt.Errorf("username and password required")
+	"codemate/credential"
+	"codemate/user"
+	"codemate/validate"
+)
+
+// authRequest carries login credentials through validate.Struct so presence,
+// charset and complexity rules are enforced in one pass instead of ad hoc
+// string checks. It is applied only on login; AuthService.Register and
+// ChangePassword gate new and changed passwords with credential.CheckStrength's
+// entropy estimate alone.
+type authRequest struct {
+	Username string `validate:"required,username,min=3,max=32"`
+	Password string `validate:"required,min=8,password"`
+}
+
+// AuthService authenticates and registers credentials against a
+// CredentialStore, hashing with the configured Hasher and transparently
+// rehashing on login when stored parameters fall behind current policy.
+type AuthService struct {
+	store          credential.CredentialStore
+	hasher         credential.Hasher
+	minEntropyBits float64
+}
+
+// NewAuthService returns an AuthService backed by store and hasher, gating
+// new and changed passwords at credential.MinEntropyBits of entropy.
+func NewAuthService(store credential.CredentialStore, hasher credential.Hasher) *AuthService {
+	return &AuthService{store: store, hasher: hasher, minEntropyBits: credential.MinEntropyBits}
+}
+
+// Register hashes and stores password for username, rejecting it if it is
+// too weak per the entropy gate.
+func (a *AuthService) Register(username, password string) error {
+	if err := credential.CheckStrength(password, a.minEntropyBits); err != nil {
+		return err
 	}
-	return true, nil
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+	return a.store.Register(username, hash)
 }
 
-// User represents a user in the system.
-type User struct {
-	ID       int64
-	Username string
-	Email    string
+// ChangePassword re-gates and re-hashes password for an existing username.
+func (a *AuthService) ChangePassword(username, password string) error {
+	if err := credential.CheckStrength(password, a.minEntropyBits); err != nil {
+		return err
+	}
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("change password: %w", err)
+	}
+	return a.store.UpdatePassword(username, hash)
 }
 
-// UserService provides operations on users.
-type UserService interface {
-	GetUser(id int64) (*User, error)
-	CreateUser(username, email string) (*User, error)
+// Authenticate looks up username's stored hash, verifies password against it
+// in constant time, and transparently rehashes and persists the credential
+// if it was stored with weaker-than-current parameters.
+func (a *AuthService) Authenticate(username, password string) (bool, error) {
+	hash, err := a.store.Lookup(username)
+	if err != nil {
+		return false, fmt.Errorf("authenticate: %w", err)
+	}
+
+	ok, err := a.hasher.Verify(hash, password)
+	if err != nil {
+		return false, fmt.Errorf("authenticate: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	if a.hasher.NeedsRehash(hash) {
+		if rehashed, err := a.hasher.Hash(password); err == nil {
+			_ = a.store.UpdatePassword(username, rehashed)
+		}
+	}
+
+	return true, nil
+}
+
+var (
+	defaultUserService = user.NewInMemoryService()
+	defaultAuthService = NewAuthService(
+		credential.NewInMemoryCredentialStore(),
+		credential.NewArgon2idHasher(credential.DefaultArgon2Params()),
+	)
+)
+
+// Authenticate authenticates a user with username and password against the
+// package's default AuthService.
+func Authenticate(username, password string) (bool, error) {
+	req := authRequest{Username: username, Password: password}
+	if err := validate.Struct(&req); err != nil {
+		return false, fmt.Errorf("authenticate: %w", err)
+	}
+	return defaultAuthService.Authenticate(username, password)
 }
 
 // ValidateEmail checks if an email address is valid.
 func ValidateEmail(email string) bool {
-	return len(email) > 3 && email != ""
+	return validate.Email(email) == nil
 }
 
 func main() {
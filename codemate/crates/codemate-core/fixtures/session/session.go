@@ -0,0 +1,328 @@
+// Package session turns a successful Authenticate call into an opaque
+// session token, tracks it per device, and provides HTTP middleware that
+// resolves a request's token back to a *user.User.
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"codemate/user"
+)
+
+// tokenBytes is the size of a raw session/CSRF token before encoding, large
+// enough that brute-forcing it is infeasible.
+const tokenBytes = 32
+
+// Session is one issued, possibly-revoked login session.
+type Session struct {
+	Token      string
+	UserID     int64
+	DeviceID   string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+}
+
+// Expired reports whether the session is past its expiry as of now.
+func (s *Session) Expired(now time.Time) bool {
+	return now.After(s.ExpiresAt)
+}
+
+// Store persists sessions. Implementations must treat Token as the primary
+// key.
+type Store interface {
+	Create(s *Session) error
+	Get(token string) (*Session, error)
+	Touch(token string, expiresAt time.Time) error
+	Revoke(token string) error
+	RevokeAll(userID int64) error
+}
+
+// ErrNotFound is returned by Store.Get for an unknown or revoked token.
+var ErrNotFound = fmt.Errorf("session: not found")
+
+// InMemoryStore is a map-backed Store reference implementation.
+type InMemoryStore struct {
+	mu      sync.Mutex
+	byToken map[string]*Session
+}
+
+// NewInMemoryStore returns an empty Store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{byToken: make(map[string]*Session)}
+}
+
+func (s *InMemoryStore) Create(sess *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byToken[sess.Token] = sess
+	return nil
+}
+
+func (s *InMemoryStore) Get(token string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.byToken[token]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cp := *sess
+	return &cp, nil
+}
+
+func (s *InMemoryStore) Touch(token string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.byToken[token]
+	if !ok {
+		return ErrNotFound
+	}
+	sess.LastSeenAt = time.Now()
+	sess.ExpiresAt = expiresAt
+	return nil
+}
+
+func (s *InMemoryStore) Revoke(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.byToken, token)
+	return nil
+}
+
+func (s *InMemoryStore) RevokeAll(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, sess := range s.byToken {
+		if sess.UserID == userID {
+			delete(s.byToken, token)
+		}
+	}
+	return nil
+}
+
+// SQLStore is a reference Store implementation over a `sessions` table
+// (token TEXT PRIMARY KEY, user_id BIGINT, device_id TEXT, created_at,
+// last_seen_at, expires_at), using database/sql so any driver works.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore returns a Store backed by db.
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) Create(sess *Session) error {
+	_, err := s.db.Exec(
+		`INSERT INTO sessions (token, user_id, device_id, created_at, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		sess.Token, sess.UserID, sess.DeviceID, sess.CreatedAt, sess.LastSeenAt, sess.ExpiresAt,
+	)
+	return err
+}
+
+func (s *SQLStore) Get(token string) (*Session, error) {
+	sess := &Session{Token: token}
+	row := s.db.QueryRow(
+		`SELECT user_id, device_id, created_at, last_seen_at, expires_at FROM sessions WHERE token = ?`,
+		token,
+	)
+	err := row.Scan(&sess.UserID, &sess.DeviceID, &sess.CreatedAt, &sess.LastSeenAt, &sess.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (s *SQLStore) Touch(token string, expiresAt time.Time) error {
+	_, err := s.db.Exec(`UPDATE sessions SET last_seen_at = ?, expires_at = ? WHERE token = ?`, time.Now(), expiresAt, token)
+	return err
+}
+
+func (s *SQLStore) Revoke(token string) error {
+	_, err := s.db.Exec(`DELETE FROM sessions WHERE token = ?`, token)
+	return err
+}
+
+func (s *SQLStore) RevokeAll(userID int64) error {
+	_, err := s.db.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID)
+	return err
+}
+
+// Manager issues and resolves sessions for a UserService, sliding each
+// session's expiry forward on use. CookieSecret signs the token before it
+// goes into a cookie, so a tampered cookie is rejected before the token is
+// even looked up in Store.
+type Manager struct {
+	Store         Store
+	Users         user.Service
+	TTL           time.Duration
+	SlidingWindow time.Duration
+	CookieSecret  []byte
+}
+
+// NewManager returns a Manager with a 30-minute sliding TTL, signing cookies
+// under cookieSecret.
+func NewManager(store Store, users user.Service, cookieSecret []byte) *Manager {
+	return &Manager{
+		Store:         store,
+		Users:         users,
+		TTL:           30 * time.Minute,
+		SlidingWindow: 30 * time.Minute,
+		CookieSecret:  cookieSecret,
+	}
+}
+
+// Issue creates and persists a new session for userID/deviceID and returns
+// it along with the signed cookie value to set on the response.
+func (m *Manager) Issue(userID int64, deviceID string) (*Session, string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("session: issue: %w", err)
+	}
+
+	now := time.Now()
+	sess := &Session{
+		Token:      token,
+		UserID:     userID,
+		DeviceID:   deviceID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(m.TTL),
+	}
+	if err := m.Store.Create(sess); err != nil {
+		return nil, "", fmt.Errorf("session: issue: %w", err)
+	}
+	return sess, SignCookieValue(token, m.CookieSecret), nil
+}
+
+// Resolve looks up token, rejects it if expired, slides its expiry forward,
+// and returns the user it belongs to.
+func (m *Manager) Resolve(token string) (*user.User, error) {
+	sess, err := m.Store.Get(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if sess.Expired(now) {
+		_ = m.Store.Revoke(token)
+		return nil, ErrNotFound
+	}
+
+	if err := m.Store.Touch(token, now.Add(m.SlidingWindow)); err != nil {
+		return nil, fmt.Errorf("session: resolve: %w", err)
+	}
+
+	return m.Users.GetUser(sess.UserID)
+}
+
+// RevokeAll revokes every session belonging to userID, e.g. after a
+// password change.
+func (m *Manager) RevokeAll(userID int64) error {
+	return m.Store.RevokeAll(userID)
+}
+
+type contextKey int
+
+const userContextKey contextKey = 0
+
+// RequireUser resolves the session cookie named cookieName, verifying its
+// signature before ever looking up the token it carries, injects the
+// resolved *user.User into the request context, and rejects the request
+// with 401 if the cookie is missing, unsigned, unknown, or expired.
+func (m *Manager) RequireUser(cookieName string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie(cookieName)
+		if err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		token, err := VerifyCookieValue(c.Value, m.CookieSecret)
+		if err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		u, err := m.Resolve(token)
+		if err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userContextKey, u)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// UserFromContext returns the *user.User injected by RequireUser, if any.
+func UserFromContext(ctx context.Context) (*user.User, bool) {
+	u, ok := ctx.Value(userContextKey).(*user.User)
+	return u, ok
+}
+
+// randomToken returns a crypto/rand-sourced, base64url-encoded token.
+func randomToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SignCookieValue HMAC-signs value under secret so a tampered cookie is
+// detected before the token is even looked up in Store.
+func SignCookieValue(value string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(value))
+	return value + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCookieValue checks a value produced by SignCookieValue and returns
+// the original value with the signature stripped.
+func VerifyCookieValue(signed string, secret []byte) (string, error) {
+	value, sig, ok := strings.Cut(signed, ".")
+	if !ok {
+		return "", fmt.Errorf("session: malformed signed cookie")
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(value))
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if subtle.ConstantTimeCompare([]byte(sig), []byte(want)) != 1 {
+		return "", fmt.Errorf("session: signature mismatch")
+	}
+	return value, nil
+}
+
+// GenerateCSRFToken returns a fresh random token suitable for the
+// double-submit-cookie pattern: send it in both a cookie and a request
+// header/field, and compare the two with CheckCSRFToken.
+func GenerateCSRFToken() (string, error) {
+	return randomToken()
+}
+
+// CheckCSRFToken constant-time compares the cookie and request-supplied CSRF
+// tokens of the double-submit pattern.
+func CheckCSRFToken(cookieToken, requestToken string) bool {
+	if cookieToken == "" || requestToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookieToken), []byte(requestToken)) == 1
+}
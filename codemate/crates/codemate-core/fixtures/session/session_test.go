@@ -0,0 +1,127 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"codemate/user"
+)
+
+const cookieName = "session"
+
+func newTestManager(t *testing.T) (*Manager, *user.User) {
+	t.Helper()
+	users := user.NewInMemoryService()
+	u, err := users.CreateUser("alice", "alice@example.com")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	m := NewManager(NewInMemoryStore(), users, []byte("test-secret"))
+	return m, u
+}
+
+func TestManagerIssueAndRequireUser(t *testing.T) {
+	m, u := newTestManager(t)
+
+	_, cookieValue, err := m.Issue(u.ID, "device-1")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	var resolved *user.User
+	handler := m.RequireUser(cookieName, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resolved, _ = UserFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: cookieName, Value: cookieValue})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if resolved == nil || resolved.ID != u.ID {
+		t.Fatalf("resolved user = %+v, want ID %d", resolved, u.ID)
+	}
+}
+
+func TestRequireUserRejectsTamperedCookie(t *testing.T) {
+	m, u := newTestManager(t)
+
+	_, cookieValue, err := m.Issue(u.ID, "device-1")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	handler := m.RequireUser(cookieName, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not run for a tampered cookie")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: cookieName, Value: cookieValue + "tampered"})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRequireUserRejectsMissingCookie(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	handler := m.RequireUser(cookieName, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not run without a cookie")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestSignAndVerifyCookieValue(t *testing.T) {
+	secret := []byte("another-secret")
+	signed := SignCookieValue("raw-token", secret)
+
+	value, err := VerifyCookieValue(signed, secret)
+	if err != nil {
+		t.Fatalf("VerifyCookieValue: %v", err)
+	}
+	if value != "raw-token" {
+		t.Errorf("value = %q, want %q", value, "raw-token")
+	}
+
+	if _, err := VerifyCookieValue(signed+"x", secret); err == nil {
+		t.Error("VerifyCookieValue should reject a tampered signed value")
+	}
+	if _, err := VerifyCookieValue(signed, []byte("wrong-secret")); err == nil {
+		t.Error("VerifyCookieValue should reject a value signed under a different secret")
+	}
+}
+
+func TestCheckCSRFToken(t *testing.T) {
+	token, err := GenerateCSRFToken()
+	if err != nil {
+		t.Fatalf("GenerateCSRFToken: %v", err)
+	}
+	if !CheckCSRFToken(token, token) {
+		t.Error("CheckCSRFToken should accept matching tokens")
+	}
+	if CheckCSRFToken(token, "") {
+		t.Error("CheckCSRFToken should reject an empty request token")
+	}
+	other, err := GenerateCSRFToken()
+	if err != nil {
+		t.Fatalf("GenerateCSRFToken: %v", err)
+	}
+	if CheckCSRFToken(token, other) {
+		t.Error("CheckCSRFToken should reject mismatched tokens")
+	}
+}
@@ -0,0 +1,63 @@
+// Package user holds the core User and UserService types shared by the
+// various login subsystems (credential, webauthn, extauth, session) so none
+// of them need to import package main.
+package user
+
+import (
+	"fmt"
+	"sync"
+
+	"codemate/credential"
+	"codemate/validate"
+)
+
+// User represents a user in the system.
+type User struct {
+	ID          int64
+	Username    string `validate:"required,username,min=3,max=32"`
+	Email       string `validate:"required,email"`
+	Credentials []credential.WebAuthnCredential
+}
+
+// Service provides operations on users.
+type Service interface {
+	GetUser(id int64) (*User, error)
+	CreateUser(username, email string) (*User, error)
+}
+
+// inMemoryService is a minimal Service backed by a map, kept around as a
+// reference implementation for the validation subsystem.
+type inMemoryService struct {
+	mu     sync.Mutex
+	nextID int64
+	users  map[int64]*User
+}
+
+// NewInMemoryService returns a Service with an empty user set.
+func NewInMemoryService() Service {
+	return &inMemoryService{users: make(map[int64]*User)}
+}
+
+func (s *inMemoryService) GetUser(id int64) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user %d not found", id)
+	}
+	return u, nil
+}
+
+func (s *inMemoryService) CreateUser(username, email string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := &User{ID: s.nextID + 1, Username: username, Email: email}
+	if err := validate.Struct(u); err != nil {
+		return nil, fmt.Errorf("create user: %w", err)
+	}
+
+	s.nextID++
+	s.users[u.ID] = u
+	return u, nil
+}
@@ -0,0 +1,289 @@
+// Package validate provides struct-tag driven validation, in the spirit of
+// go-playground/validator: annotate a struct with `validate:"..."` tags and
+// call Struct to get back every failing field in one pass.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FieldError describes a single failing field.
+type FieldError struct {
+	Field string
+	Tag   string
+	Param string
+}
+
+func (e *FieldError) Error() string {
+	if e.Param != "" {
+		return fmt.Sprintf("%s failed on %q (%s)", e.Field, e.Tag, e.Param)
+	}
+	return fmt.Sprintf("%s failed on %q", e.Field, e.Tag)
+}
+
+// Errors is a multi-error collecting every FieldError produced by Struct.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// RuleFunc validates a single reflect.Value against an optional parameter
+// (the part after "=" in a tag such as "min=3") and returns an error if the
+// value is invalid.
+type RuleFunc func(v reflect.Value, param string) error
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]RuleFunc{
+		"required": required,
+		"min":      min,
+		"max":      max,
+		"email":    emailRule,
+		"username": usernameRule,
+		"password": passwordRule,
+	}
+
+	usernameRe = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+)
+
+// RegisterValidator adds or overrides a named rule. fn is invoked with the
+// field's reflect.Value only; any parameter after "=" in the tag is not
+// passed to it, so callers needing richer access can close over additional
+// state.
+func RegisterValidator(name string, fn func(reflect.Value) error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = func(v reflect.Value, _ string) error { return fn(v) }
+}
+
+// fieldMeta is the parsed, cached form of a single struct field's tag.
+type fieldMeta struct {
+	index int
+	name  string
+	rules []rule
+	dive  bool
+}
+
+type rule struct {
+	name  string
+	param string
+}
+
+var typeCache sync.Map // reflect.Type -> []fieldMeta
+
+func metaFor(t reflect.Type) []fieldMeta {
+	if cached, ok := typeCache.Load(t); ok {
+		return cached.([]fieldMeta)
+	}
+
+	metas := make([]fieldMeta, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		fm := fieldMeta{index: i, name: f.Name}
+		for _, part := range strings.Split(tag, ",") {
+			if part == "dive" {
+				fm.dive = true
+				continue
+			}
+			name, param, _ := strings.Cut(part, "=")
+			fm.rules = append(fm.rules, rule{name: name, param: param})
+		}
+		metas = append(metas, fm)
+	}
+
+	actual, _ := typeCache.LoadOrStore(t, metas)
+	return actual.([]fieldMeta)
+}
+
+// Struct validates every tagged field of v, which must be a struct or a
+// pointer to one, and returns an Errors value listing every failure (nil if
+// v is valid). A nil pointer yields a single *FieldError instead, and a
+// non-struct v a plain error.
+func Struct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return &FieldError{Field: "<root>", Tag: "required"}
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: Struct() called with non-struct type %s", rv.Kind())
+	}
+
+	var errs Errors
+	for _, fm := range metaFor(rv.Type()) {
+		fv := rv.Field(fm.index)
+
+		for _, r := range fm.rules {
+			registryMu.RLock()
+			fn, ok := registry[r.name]
+			registryMu.RUnlock()
+			if !ok {
+				errs = append(errs, &FieldError{Field: fm.name, Tag: r.name, Param: "unknown rule"})
+				continue
+			}
+			if err := fn(fv, r.param); err != nil {
+				errs = append(errs, &FieldError{Field: fm.name, Tag: r.name, Param: r.param})
+			}
+		}
+
+		if fm.dive {
+			errs = append(errs, diveInto(fm.name, fv)...)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func diveInto(fieldName string, fv reflect.Value) Errors {
+	var errs Errors
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := Struct(fv.Index(i).Interface()); err != nil {
+				if fe, ok := err.(Errors); ok {
+					for _, e := range fe {
+						e.Field = fmt.Sprintf("%s[%d].%s", fieldName, i, e.Field)
+						errs = append(errs, e)
+					}
+				}
+			}
+		}
+	case reflect.Ptr:
+		if !fv.IsNil() {
+			if err := Struct(fv.Interface()); err != nil {
+				if fe, ok := err.(Errors); ok {
+					errs = append(errs, fe...)
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func required(v reflect.Value, _ string) error {
+	if v.IsZero() {
+		return fmt.Errorf("required")
+	}
+	return nil
+}
+
+func min(v reflect.Value, param string) error {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return fmt.Errorf("invalid min param %q", param)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		if len(v.String()) < n {
+			return fmt.Errorf("below min")
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if v.Len() < n {
+			return fmt.Errorf("below min")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < int64(n) {
+			return fmt.Errorf("below min")
+		}
+	}
+	return nil
+}
+
+func max(v reflect.Value, param string) error {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return fmt.Errorf("invalid max param %q", param)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		if len(v.String()) > n {
+			return fmt.Errorf("above max")
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if v.Len() > n {
+			return fmt.Errorf("above max")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() > int64(n) {
+			return fmt.Errorf("above max")
+		}
+	}
+	return nil
+}
+
+func emailRule(v reflect.Value, _ string) error {
+	if v.Kind() != reflect.String {
+		return fmt.Errorf("email rule requires a string field")
+	}
+	return Email(v.String())
+}
+
+// Email reports whether addr is a single, exact RFC 5322 address (no display
+// name, no trailing garbage that mail.ParseAddress would otherwise accept as
+// part of a list).
+func Email(addr string) error {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return fmt.Errorf("invalid email: %w", err)
+	}
+	if parsed.Address != addr {
+		return fmt.Errorf("invalid email: must be a bare address")
+	}
+	return nil
+}
+
+func usernameRule(v reflect.Value, _ string) error {
+	if v.Kind() != reflect.String {
+		return fmt.Errorf("username rule requires a string field")
+	}
+	if !usernameRe.MatchString(v.String()) {
+		return fmt.Errorf("username contains invalid characters")
+	}
+	return nil
+}
+
+// passwordRule enforces at least one lowercase letter, one uppercase letter,
+// one digit and one symbol.
+func passwordRule(v reflect.Value, _ string) error {
+	if v.Kind() != reflect.String {
+		return fmt.Errorf("password rule requires a string field")
+	}
+	s := v.String()
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+	if !hasLower || !hasUpper || !hasDigit || !hasSymbol {
+		return fmt.Errorf("password must contain lower, upper, digit and symbol characters")
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type dtoWithRules struct {
+	Username string `validate:"required,username,min=3,max=32"`
+	Email    string `validate:"required,email"`
+	Password string `validate:"required,min=8,password"`
+}
+
+func TestStructCollectsEveryFailure(t *testing.T) {
+	dto := dtoWithRules{Username: "a!", Email: "not-an-email", Password: "short"}
+
+	err := Struct(&dto)
+	if err == nil {
+		t.Fatal("Struct should reject an invalid DTO")
+	}
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("Struct returned %T, want Errors", err)
+	}
+
+	tags := map[string]bool{}
+	for _, fe := range errs {
+		tags[fe.Field+"/"+fe.Tag] = true
+	}
+	for _, want := range []string{"Username/username", "Username/min", "Email/email", "Password/password"} {
+		if !tags[want] {
+			t.Errorf("missing failure for %s, got %v", want, errs)
+		}
+	}
+}
+
+func TestStructAcceptsValidDTO(t *testing.T) {
+	dto := dtoWithRules{Username: "alice", Email: "alice@example.com", Password: "Tr0ub4dor&3"}
+	if err := Struct(&dto); err != nil {
+		t.Fatalf("Struct rejected a valid DTO: %v", err)
+	}
+}
+
+func TestStructRejectsNilPointer(t *testing.T) {
+	var dto *dtoWithRules
+	if err := Struct(dto); err == nil {
+		t.Error("Struct should reject a nil pointer")
+	}
+}
+
+func TestStructDivesIntoSlice(t *testing.T) {
+	type batch struct {
+		Users []dtoWithRules `validate:"dive"`
+	}
+	b := batch{Users: []dtoWithRules{
+		{Username: "alice", Email: "alice@example.com", Password: "Tr0ub4dor&3"},
+		{Username: "a!", Email: "not-an-email", Password: "short"},
+	}}
+
+	err := Struct(&b)
+	if err == nil {
+		t.Fatal("Struct should reject a batch with an invalid element")
+	}
+	errs := err.(Errors)
+	for _, fe := range errs {
+		if fe.Field[:8] != "Users[1]" {
+			t.Errorf("failure %q should be scoped to Users[1], not Users[0]", fe.Field)
+		}
+	}
+}
+
+func TestRegisterValidator(t *testing.T) {
+	type dto struct {
+		Code string `validate:"evenlen"`
+	}
+	RegisterValidator("evenlen", func(v reflect.Value) error {
+		if len(v.String())%2 != 0 {
+			return fmt.Errorf("odd length")
+		}
+		return nil
+	})
+
+	if err := Struct(&dto{Code: "abc"}); err == nil {
+		t.Error("evenlen should reject an odd-length string")
+	}
+	if err := Struct(&dto{Code: "abcd"}); err != nil {
+		t.Errorf("evenlen should accept an even-length string: %v", err)
+	}
+}
+
+func TestRequiredRule(t *testing.T) {
+	type dto struct {
+		Name string `validate:"required"`
+	}
+	if err := Struct(&dto{}); err == nil {
+		t.Error("required should reject a zero-value field")
+	}
+	if err := Struct(&dto{Name: "x"}); err != nil {
+		t.Errorf("required should accept a non-zero field: %v", err)
+	}
+}
+
+func TestMinMaxRules(t *testing.T) {
+	type dto struct {
+		Name string `validate:"min=3,max=5"`
+	}
+	if err := Struct(&dto{Name: "ab"}); err == nil {
+		t.Error("min should reject a too-short string")
+	}
+	if err := Struct(&dto{Name: "abcdef"}); err == nil {
+		t.Error("max should reject a too-long string")
+	}
+	if err := Struct(&dto{Name: "abcd"}); err != nil {
+		t.Errorf("min/max should accept a string in range: %v", err)
+	}
+}
+
+func TestEmailRule(t *testing.T) {
+	if err := Email("alice@example.com"); err != nil {
+		t.Errorf("Email rejected a valid address: %v", err)
+	}
+	if err := Email("Alice <alice@example.com>"); err == nil {
+		t.Error("Email should reject a display name, not just a bare address")
+	}
+	if err := Email("not-an-email"); err == nil {
+		t.Error("Email should reject a malformed address")
+	}
+}
+
+func TestUsernameRule(t *testing.T) {
+	type dto struct {
+		Name string `validate:"username"`
+	}
+	if err := Struct(&dto{Name: "alice_the-2nd.one"}); err != nil {
+		t.Errorf("username should accept letters, digits, '_', '.' and '-': %v", err)
+	}
+	if err := Struct(&dto{Name: "alice smith"}); err == nil {
+		t.Error("username should reject a space")
+	}
+}
+
+func TestPasswordRule(t *testing.T) {
+	type dto struct {
+		Password string `validate:"password"`
+	}
+	if err := Struct(&dto{Password: "alllowercase"}); err == nil {
+		t.Error("password should reject a string missing upper/digit/symbol classes")
+	}
+	if err := Struct(&dto{Password: "Tr0ub4dor&3"}); err != nil {
+		t.Errorf("password should accept a string covering all four classes: %v", err)
+	}
+}
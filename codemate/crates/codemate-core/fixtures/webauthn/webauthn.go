@@ -0,0 +1,513 @@
+// Package webauthn implements enough of the W3C WebAuthn Level 2
+// specification to register and assert FIDO2/passkey credentials as a
+// passwordless or second-factor login method for UserService: building
+// registration/assertion options, and verifying the browser's attestation
+// and assertion responses.
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"codemate/credential"
+)
+
+// RelyingParty identifies the site performing WebAuthn ceremonies.
+type RelyingParty struct {
+	ID     string // effective domain, e.g. "example.com"
+	Name   string
+	Origin string // exact origin browsers must report, e.g. "https://example.com"
+}
+
+// ChallengeStore holds outstanding registration/login challenges, keyed by
+// the user (or session) they were issued to, until they expire or are
+// consumed.
+type ChallengeStore interface {
+	Save(key string, challenge []byte, ttl time.Duration) error
+	Consume(key string) ([]byte, error) // returns and deletes; errors once expired or consumed
+}
+
+type challengeEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// InMemoryChallengeStore is a map-backed ChallengeStore reference
+// implementation, guarded by a mutex.
+type InMemoryChallengeStore struct {
+	mu      sync.Mutex
+	entries map[string]challengeEntry
+}
+
+// NewInMemoryChallengeStore returns an empty ChallengeStore.
+func NewInMemoryChallengeStore() *InMemoryChallengeStore {
+	return &InMemoryChallengeStore{entries: make(map[string]challengeEntry)}
+}
+
+func (s *InMemoryChallengeStore) Save(key string, challenge []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = challengeEntry{value: challenge, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *InMemoryChallengeStore) Consume(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	delete(s.entries, key)
+	if !ok {
+		return nil, fmt.Errorf("webauthn: no challenge pending for %q", key)
+	}
+	if time.Now().After(e.expiresAt) {
+		return nil, fmt.Errorf("webauthn: challenge for %q expired", key)
+	}
+	return e.value, nil
+}
+
+// relyingParty and user sub-objects of PublicKeyCredentialCreationOptions.
+type rpEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type userEntity struct {
+	ID          string `json:"id"` // base64url(User.ID), the WebAuthn user handle
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type pubKeyCredParam struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type credentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"` // base64url
+	Transports []string `json:"transports,omitempty"`
+}
+
+type authenticatorSelection struct {
+	UserVerification string `json:"userVerification"`
+}
+
+// RegistrationOptions mirrors PublicKeyCredentialCreationOptions, ready to
+// be serialized to JSON and handed to navigator.credentials.create() on the
+// client.
+type RegistrationOptions struct {
+	Challenge              string                 `json:"challenge"` // base64url
+	RP                     rpEntity               `json:"rp"`
+	User                   userEntity             `json:"user"`
+	PubKeyCredParams       []pubKeyCredParam      `json:"pubKeyCredParams"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	ExcludeCredentials     []credentialDescriptor `json:"excludeCredentials,omitempty"`
+	Timeout                int                    `json:"timeout"`
+}
+
+// Registration algorithms offered to the authenticator: ES256 only, the one
+// algorithm verifyCOSESignature can check at login.
+var defaultPubKeyCredParams = []pubKeyCredParam{
+	{Type: "public-key", Alg: -7}, // ES256
+}
+
+const challengeTTL = 5 * time.Minute
+
+// BeginRegistration issues a fresh challenge for userID/username, persists
+// it in store, and returns the options JSON for
+// navigator.credentials.create(). existing is the user's already-registered
+// credentials, echoed back as excludeCredentials so the authenticator
+// refuses to re-register one of them.
+func BeginRegistration(rp RelyingParty, userID int64, username, displayName string, existing []credential.WebAuthnCredential, store ChallengeStore) (*RegistrationOptions, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("webauthn: generate challenge: %w", err)
+	}
+
+	key := fmt.Sprintf("register:%d", userID)
+	if err := store.Save(key, challenge, challengeTTL); err != nil {
+		return nil, fmt.Errorf("webauthn: save challenge: %w", err)
+	}
+
+	exclude := make([]credentialDescriptor, 0, len(existing))
+	for _, c := range existing {
+		exclude = append(exclude, credentialDescriptor{
+			Type:       "public-key",
+			ID:         b64url(c.ID),
+			Transports: c.Transports,
+		})
+	}
+
+	return &RegistrationOptions{
+		Challenge: b64url(challenge),
+		RP:        rpEntity{ID: rp.ID, Name: rp.Name},
+		User: userEntity{
+			ID:          b64url(userHandle(userID)),
+			Name:        username,
+			DisplayName: displayName,
+		},
+		PubKeyCredParams:       defaultPubKeyCredParams,
+		AuthenticatorSelection: authenticatorSelection{UserVerification: "preferred"},
+		ExcludeCredentials:     exclude,
+		Timeout:                60000,
+	}, nil
+}
+
+// userHandle derives a stable WebAuthn user handle from a User.ID.
+func userHandle(userID int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(userID))
+	return b
+}
+
+// AttestationResponse is the subset of
+// PublicKeyCredential/AuthenticatorAttestationResponse the client posts back
+// after navigator.credentials.create().
+type AttestationResponse struct {
+	ID       string `json:"id"` // base64url credential ID
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		AttestationObject string `json:"attestationObject"` // base64url CBOR
+		ClientDataJSON    string `json:"clientDataJSON"`    // base64url JSON
+	} `json:"response"`
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type attestationObject struct {
+	Fmt      string                 `cbor:"fmt"`
+	AttStmt  map[string]interface{} `cbor:"attStmt"`
+	AuthData []byte                 `cbor:"authData"`
+}
+
+const (
+	flagUserPresent  = 1 << 0
+	flagUserVerified = 1 << 2
+	flagAttestedData = 1 << 6
+)
+
+type authData struct {
+	RPIDHash      [32]byte
+	Flags         byte
+	SignCount     uint32
+	AAGUID        []byte
+	CredentialID  []byte
+	CredPublicKey []byte // remaining CBOR bytes after the credential ID, re-parsed per algorithm
+}
+
+// parseAuthData splits the flat authenticatorData byte string into its
+// fields per §6.1 of the spec.
+func parseAuthData(raw []byte) (*authData, error) {
+	if len(raw) < 37 {
+		return nil, fmt.Errorf("webauthn: authData too short")
+	}
+	ad := &authData{Flags: raw[32]}
+	copy(ad.RPIDHash[:], raw[:32])
+	ad.SignCount = binary.BigEndian.Uint32(raw[33:37])
+
+	if ad.Flags&flagAttestedData == 0 {
+		return ad, nil
+	}
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("webauthn: attested credential data truncated")
+	}
+	ad.AAGUID = rest[:16]
+	credIDLen := binary.BigEndian.Uint16(rest[16:18])
+	rest = rest[18:]
+	if len(rest) < int(credIDLen) {
+		return nil, fmt.Errorf("webauthn: credential id truncated")
+	}
+	ad.CredentialID = rest[:credIDLen]
+	ad.CredPublicKey = rest[credIDLen:] // CBOR COSE_Key, possibly followed by extensions we don't need
+
+	return ad, nil
+}
+
+// FinishRegistration verifies resp against the challenge previously issued
+// for userID and the relying party's expected origin/RP ID, and returns the
+// credential to persist via CredentialStore.SaveWebAuthnCredential.
+func FinishRegistration(rp RelyingParty, store ChallengeStore, userID int64, resp AttestationResponse) (*credential.WebAuthnCredential, error) {
+	challenge, err := store.Consume(fmt.Sprintf("register:%d", userID))
+	if err != nil {
+		return nil, err
+	}
+
+	cd, err := decodeClientData(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	if cd.Type != "webauthn.create" {
+		return nil, fmt.Errorf("webauthn: unexpected clientData type %q", cd.Type)
+	}
+	if err := checkChallengeAndOrigin(cd, challenge, rp); err != nil {
+		return nil, err
+	}
+
+	rawAttObj, err := b64urlDecode(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("webauthn: decode attestationObject: %w", err)
+	}
+	var attObj attestationObject
+	if err := cbor.Unmarshal(rawAttObj, &attObj); err != nil {
+		return nil, fmt.Errorf("webauthn: cbor decode attestationObject: %w", err)
+	}
+
+	ad, err := parseAuthData(attObj.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRPIDAndFlags(rp, ad); err != nil {
+		return nil, err
+	}
+	if ad.CredentialID == nil {
+		return nil, fmt.Errorf("webauthn: authData has no attested credential data")
+	}
+
+	// Attestation statement verification. "none" carries nothing to verify.
+	// "packed" and "fido-u2f" both sign authData||clientDataHash; for
+	// self-attestation (no x5c chain) we verify directly against the
+	// credential's own public key, which is the common case for platform
+	// authenticators (Touch ID, Windows Hello, passkeys).
+	switch attObj.Fmt {
+	case "none":
+		// nothing to verify
+	case "packed", "fido-u2f":
+		clientDataHash := sha256.Sum256([]byte(mustJSONRoundTrip(resp.Response.ClientDataJSON)))
+		sig, _ := attObj.AttStmt["sig"].([]byte)
+		if sig == nil {
+			return nil, fmt.Errorf("webauthn: %s attestation missing sig", attObj.Fmt)
+		}
+		signed := append(append([]byte{}, attObj.AuthData...), clientDataHash[:]...)
+		if err := verifyCOSESignature(ad.CredPublicKey, signed, sig); err != nil {
+			return nil, fmt.Errorf("webauthn: %s attestation signature: %w", attObj.Fmt, err)
+		}
+	default:
+		return nil, fmt.Errorf("webauthn: unsupported attestation format %q", attObj.Fmt)
+	}
+
+	return &credential.WebAuthnCredential{
+		ID:        ad.CredentialID,
+		PublicKey: ad.CredPublicKey,
+		SignCount: ad.SignCount,
+		AAGUID:    ad.AAGUID,
+	}, nil
+}
+
+// AssertionOptions mirrors PublicKeyCredentialRequestOptions.
+type AssertionOptions struct {
+	Challenge        string                 `json:"challenge"` // base64url
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []credentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification"`
+	Timeout          int                    `json:"timeout"`
+}
+
+// BeginLogin issues a fresh challenge for userID and returns the options
+// JSON for navigator.credentials.get().
+func BeginLogin(rp RelyingParty, userID int64, creds []credential.WebAuthnCredential, store ChallengeStore) (*AssertionOptions, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("webauthn: generate challenge: %w", err)
+	}
+
+	key := fmt.Sprintf("login:%d", userID)
+	if err := store.Save(key, challenge, challengeTTL); err != nil {
+		return nil, fmt.Errorf("webauthn: save challenge: %w", err)
+	}
+
+	allow := make([]credentialDescriptor, 0, len(creds))
+	for _, c := range creds {
+		allow = append(allow, credentialDescriptor{Type: "public-key", ID: b64url(c.ID), Transports: c.Transports})
+	}
+
+	return &AssertionOptions{
+		Challenge:        b64url(challenge),
+		RPID:             rp.ID,
+		AllowCredentials: allow,
+		UserVerification: "preferred",
+		Timeout:          60000,
+	}, nil
+}
+
+// AssertionResponse is the subset of
+// PublicKeyCredential/AuthenticatorAssertionResponse the client posts back
+// after navigator.credentials.get().
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		AuthenticatorData string `json:"authenticatorData"` // base64url
+		ClientDataJSON    string `json:"clientDataJSON"`    // base64url
+		Signature         string `json:"signature"`         // base64url
+		UserHandle        string `json:"userHandle"`        // base64url, optional
+	} `json:"response"`
+}
+
+// FinishLogin verifies resp against the challenge previously issued for
+// userID, checks the authenticator's signature counter moved forward, and
+// returns the new signature count to persist via
+// CredentialStore.UpdateWebAuthnSignCount.
+func FinishLogin(rp RelyingParty, store ChallengeStore, userID int64, cred credential.WebAuthnCredential, resp AssertionResponse) (newSignCount uint32, err error) {
+	challenge, err := store.Consume(fmt.Sprintf("login:%d", userID))
+	if err != nil {
+		return 0, err
+	}
+
+	cd, err := decodeClientData(resp.Response.ClientDataJSON)
+	if err != nil {
+		return 0, err
+	}
+	if cd.Type != "webauthn.get" {
+		return 0, fmt.Errorf("webauthn: unexpected clientData type %q", cd.Type)
+	}
+	if err := checkChallengeAndOrigin(cd, challenge, rp); err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := b64urlDecode(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, fmt.Errorf("webauthn: decode authenticatorData: %w", err)
+	}
+	ad, err := parseAuthData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkRPIDAndFlags(rp, ad); err != nil {
+		return 0, err
+	}
+
+	// A zero counter on both sides means the authenticator doesn't maintain
+	// one (common for platform authenticators); otherwise it must strictly
+	// increase to catch cloned credentials.
+	if !(ad.SignCount == 0 && cred.SignCount == 0) && ad.SignCount <= cred.SignCount {
+		return 0, fmt.Errorf("webauthn: signature counter did not advance (stored %d, got %d) — possible cloned authenticator", cred.SignCount, ad.SignCount)
+	}
+
+	clientDataHash := sha256.Sum256([]byte(mustJSONRoundTrip(resp.Response.ClientDataJSON)))
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+
+	sig, err := b64urlDecode(resp.Response.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("webauthn: decode signature: %w", err)
+	}
+	if err := verifyCOSESignature(cred.PublicKey, signed, sig); err != nil {
+		return 0, fmt.Errorf("webauthn: assertion signature: %w", err)
+	}
+
+	return ad.SignCount, nil
+}
+
+func checkChallengeAndOrigin(cd *clientData, wantChallenge []byte, rp RelyingParty) error {
+	gotChallenge, err := b64urlDecode(cd.Challenge)
+	if err != nil {
+		return fmt.Errorf("webauthn: decode clientData challenge: %w", err)
+	}
+	if subtle.ConstantTimeCompare(gotChallenge, wantChallenge) != 1 {
+		return fmt.Errorf("webauthn: challenge mismatch")
+	}
+	if cd.Origin != rp.Origin {
+		return fmt.Errorf("webauthn: origin mismatch: got %q want %q", cd.Origin, rp.Origin)
+	}
+	return nil
+}
+
+func checkRPIDAndFlags(rp RelyingParty, ad *authData) error {
+	wantHash := sha256.Sum256([]byte(rp.ID))
+	if ad.RPIDHash != wantHash {
+		return fmt.Errorf("webauthn: rpIdHash mismatch")
+	}
+	if ad.Flags&flagUserPresent == 0 {
+		return fmt.Errorf("webauthn: user-present flag not set")
+	}
+	return nil
+}
+
+func decodeClientData(b64 string) (*clientData, error) {
+	raw, err := b64urlDecode(b64)
+	if err != nil {
+		return nil, fmt.Errorf("webauthn: decode clientDataJSON: %w", err)
+	}
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return nil, fmt.Errorf("webauthn: parse clientDataJSON: %w", err)
+	}
+	return &cd, nil
+}
+
+// mustJSONRoundTrip returns the original clientDataJSON bytes for hashing;
+// it exists only so the call sites read naturally (decode, then hash the
+// same bytes we decoded from).
+func mustJSONRoundTrip(b64 string) string {
+	raw, _ := b64urlDecode(b64)
+	return string(raw)
+}
+
+// COSE key type/algorithm labels (§COSE registry); of these,
+// verifyCOSESignature accepts only EC2 keys with ES256.
+const (
+	coseKtyEC2   = 2
+	coseKtyRSA   = 3
+	coseAlgES256 = -7
+)
+
+// verifyCOSESignature decodes a COSE_Key from coseKey and checks sig over
+// signed. Only EC2/ES256 (P-256) keys are supported, which covers platform
+// authenticators and passkeys; other key types are rejected explicitly
+// rather than silently accepted.
+func verifyCOSESignature(coseKey, signed, sig []byte) error {
+	var m map[int]interface{}
+	if err := cbor.Unmarshal(coseKey, &m); err != nil {
+		return fmt.Errorf("cbor decode COSE key: %w", err)
+	}
+
+	// cbor decodes a non-negative CBOR integer (kty) into uint64 when the
+	// destination is interface{}, and a negative one (alg) into int64 — they
+	// are not interchangeable.
+	ktyU, _ := m[1].(uint64)
+	kty := int64(ktyU)
+	alg, _ := m[3].(int64)
+	if kty != coseKtyEC2 || alg != coseAlgES256 {
+		return fmt.Errorf("unsupported COSE key type/alg %d/%d", kty, alg)
+	}
+
+	xBytes, _ := m[-2].([]byte)
+	yBytes, _ := m[-3].([]byte)
+	if xBytes == nil || yBytes == nil {
+		return fmt.Errorf("COSE key missing x/y coordinates")
+	}
+
+	pub := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(xBytes),
+		Y:     new(big.Int).SetBytes(yBytes),
+	}
+
+	hash := sha256.Sum256(signed)
+	if !ecdsa.VerifyASN1(pub, hash[:], sig) {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
+
+func b64url(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func b64urlDecode(s string) ([]byte, error) { return base64.RawURLEncoding.DecodeString(s) }
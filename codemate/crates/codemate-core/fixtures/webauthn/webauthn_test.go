@@ -0,0 +1,144 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"codemate/credential"
+)
+
+// buildAuthData assembles a flat authenticatorData byte string per §6.1,
+// optionally including attested credential data.
+func buildAuthData(t *testing.T, rpID string, flags byte, signCount uint32, credID, cosePubKey []byte) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte(rpID))
+
+	buf := append([]byte{}, rpIDHash[:]...)
+	buf = append(buf, flags)
+	var sc [4]byte
+	binary.BigEndian.PutUint32(sc[:], signCount)
+	buf = append(buf, sc[:]...)
+
+	if flags&flagAttestedData != 0 {
+		buf = append(buf, make([]byte, 16)...) // AAGUID, zeroed for the test
+		var idLen [2]byte
+		binary.BigEndian.PutUint16(idLen[:], uint16(len(credID)))
+		buf = append(buf, idLen[:]...)
+		buf = append(buf, credID...)
+		buf = append(buf, cosePubKey...)
+	}
+
+	return buf
+}
+
+// coseEC2Key builds a CBOR-encoded COSE_Key for an ES256 P-256 public key.
+func coseEC2Key(t *testing.T, pub *ecdsa.PublicKey) []byte {
+	t.Helper()
+	m := map[int]interface{}{
+		1:  uint64(2), // kty: EC2
+		3:  int64(-7), // alg: ES256
+		-1: uint64(1), // crv: P-256
+		-2: pub.X.Bytes(),
+		-3: pub.Y.Bytes(),
+	}
+	enc, err := cbor.Marshal(m)
+	if err != nil {
+		t.Fatalf("cbor.Marshal COSE key: %v", err)
+	}
+	return enc
+}
+
+func encodeClientData(t *testing.T, typ string, challenge []byte, origin string) (b64 string, raw []byte) {
+	t.Helper()
+	raw, err := json.Marshal(clientData{Type: typ, Challenge: b64url(challenge), Origin: origin})
+	if err != nil {
+		t.Fatalf("marshal clientData: %v", err)
+	}
+	return b64url(raw), raw
+}
+
+func TestWebAuthnRegisterAndLoginRoundTrip(t *testing.T) {
+	rp := RelyingParty{ID: "example.com", Name: "Example", Origin: "https://example.com"}
+	store := NewInMemoryChallengeStore()
+	const userID = int64(1)
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	credID := []byte("test-credential-id")
+	cosePub := coseEC2Key(t, &priv.PublicKey)
+
+	// --- registration ---
+	regOpts, err := BeginRegistration(rp, userID, "alice", "Alice", nil, store)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	challenge, err := b64urlDecode(regOpts.Challenge)
+	if err != nil {
+		t.Fatalf("decode challenge: %v", err)
+	}
+
+	authData := buildAuthData(t, rp.ID, flagUserPresent|flagAttestedData, 0, credID, cosePub)
+	attObjBytes, err := cbor.Marshal(attestationObject{Fmt: "none", AttStmt: map[string]interface{}{}, AuthData: authData})
+	if err != nil {
+		t.Fatalf("marshal attestationObject: %v", err)
+	}
+	clientDataB64, _ := encodeClientData(t, "webauthn.create", challenge, rp.Origin)
+
+	var attResp AttestationResponse
+	attResp.ID = b64url(credID)
+	attResp.Type = "public-key"
+	attResp.Response.AttestationObject = b64url(attObjBytes)
+	attResp.Response.ClientDataJSON = clientDataB64
+
+	cred, err := FinishRegistration(rp, store, userID, attResp)
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	if string(cred.ID) != string(credID) {
+		t.Errorf("cred.ID = %q, want %q", cred.ID, credID)
+	}
+
+	// --- login ---
+	loginOpts, err := BeginLogin(rp, userID, []credential.WebAuthnCredential{*cred}, store)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	loginChallenge, err := b64urlDecode(loginOpts.Challenge)
+	if err != nil {
+		t.Fatalf("decode login challenge: %v", err)
+	}
+
+	loginAuthData := buildAuthData(t, rp.ID, flagUserPresent, 1, nil, nil)
+	loginClientDataB64, loginClientDataRaw := encodeClientData(t, "webauthn.get", loginChallenge, rp.Origin)
+	clientDataHash := sha256.Sum256(loginClientDataRaw)
+	signed := append(append([]byte{}, loginAuthData...), clientDataHash[:]...)
+	digest := sha256.Sum256(signed)
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	if err != nil {
+		t.Fatalf("sign assertion: %v", err)
+	}
+
+	var assertResp AssertionResponse
+	assertResp.ID = b64url(credID)
+	assertResp.Type = "public-key"
+	assertResp.Response.AuthenticatorData = b64url(loginAuthData)
+	assertResp.Response.ClientDataJSON = loginClientDataB64
+	assertResp.Response.Signature = b64url(sig)
+
+	newSignCount, err := FinishLogin(rp, store, userID, *cred, assertResp)
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if newSignCount != 1 {
+		t.Errorf("newSignCount = %d, want 1", newSignCount)
+	}
+}